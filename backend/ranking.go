@@ -3,6 +3,7 @@ package main
 import (
 	"log"
 	"sync"
+	"time"
 )
 
 
@@ -13,8 +14,10 @@ const (
 )
 type RankingEngine struct {
 	ratingCount [RatingBucketSize]int // 5001
-	mu sync.RWMutex
-	totalUsers int
+	userBIT     [RatingBucketSize + 1]int
+	distinctBIT [RatingBucketSize + 1]int
+	mu          sync.RWMutex
+	totalUsers  int
 }
 
 var rankingEngine *RankingEngine
@@ -30,49 +33,104 @@ func InitRankingEngine() error {
 		if rating >= MinRating && rating <= MaxRating {
 			rankingEngine.ratingCount[rating] = count
 			totalUsers += count
+			if count > 0 {
+				rankingEngine.bitAdd(rankingEngine.userBIT[:], rating, count)
+				rankingEngine.bitAdd(rankingEngine.distinctBIT[:], rating, 1)
+			}
 		}
 	}
 	rankingEngine.totalUsers = totalUsers
 	log.Printf("✓ Ranking engine initialized with %d users across %d unique ratings",
 		totalUsers, len(counts))
 
+	if err := topKIndex.LoadFromDisk(TopKPersistPath); err != nil {
+		log.Printf("topk: no persisted top-K at %s (%v), rebuilding from DB", TopKPersistPath, err)
+		if err := topKIndex.RebuildFromDB(); err != nil {
+			log.Printf("topk: rebuild from DB failed, starting empty: %v", err)
+		}
+	}
+	topKIndex.StartPeriodicPersistence(TopKPersistPath, 5*time.Minute)
+
 	return nil
 }
 
+// bitAdd applies delta to the Fenwick tree at 1-indexed position r.
+func (re *RankingEngine) bitAdd(tree []int, r, delta int) {
+	for i := r; i < len(tree); i += i & -i {
+		tree[i] += delta
+	}
+}
+
+// bitSum returns the prefix sum over [1, r] of the Fenwick tree; r <= 0 yields 0.
+func (re *RankingEngine) bitSum(tree []int, r int) int {
+	s := 0
+	for i := r; i > 0; i -= i & -i {
+		s += tree[i]
+	}
+	return s
+}
+
 func (re *RankingEngine) GetRank(rating int) int {
 	re.mu.RLock()
 	defer re.mu.RUnlock()
 
-	// Dense ranking: count distinct ratings above this rating
-	rank := 1
-	for r := rating + 1; r <= MaxRating; r++ {
-		if re.ratingCount[r] > 0 {
-			rank++
-		}
+	return re.rankLocked(rating)
+}
+
+// rankLocked computes the dense rank of rating, or -1 if rating is outside
+// [MinRating, MaxRating]. Callers must hold re.mu (for reading or writing).
+func (re *RankingEngine) rankLocked(rating int) int {
+	if rating < MinRating || rating > MaxRating {
+		return -1
 	}
-	return rank
+	above := re.bitSum(re.distinctBIT[:], MaxRating) - re.bitSum(re.distinctBIT[:], rating)
+	return 1 + above
 }
 
-func (re *RankingEngine) GetRankBatch(ratings []int) []int {
+// GetCompetitionRank returns the 1-indexed standard competition rank
+// (ties share a rank, the next rank skips by the tie size), or -1 if rating
+// is outside [MinRating, MaxRating].
+func (re *RankingEngine) GetCompetitionRank(rating int) int {
 	re.mu.RLock()
 	defer re.mu.RUnlock()
 
-	// Dense ranking: count distinct ratings above each rating
-	// cumulativeAbove[r] = number of distinct ratings higher than r
-	cumulativeAbove := make([]int, RatingBucketSize)
+	if rating < MinRating || rating > MaxRating {
+		return -1
+	}
 
-	distinctCount := 0
-	for r := MaxRating; r >= MinRating; r-- {
-		cumulativeAbove[r] = distinctCount
-		if re.ratingCount[r] > 0 {
-			distinctCount++
-		}
+	above := re.bitSum(re.userBIT[:], MaxRating) - re.bitSum(re.userBIT[:], rating)
+	return 1 + above
+}
+
+// GetPercentile returns the fraction of users strictly below rating, in
+// [0, 1], or -1 if rating is outside [MinRating, MaxRating].
+func (re *RankingEngine) GetPercentile(rating int) float64 {
+	re.mu.RLock()
+	defer re.mu.RUnlock()
+
+	if rating < MinRating || rating > MaxRating {
+		return -1
+	}
+
+	total := re.bitSum(re.userBIT[:], MaxRating)
+	if total == 0 {
+		return 0
 	}
+	below := re.bitSum(re.userBIT[:], rating-1)
+	return float64(below) / float64(total)
+}
+
+func (re *RankingEngine) GetRankBatch(ratings []int) []int {
+	re.mu.RLock()
+	defer re.mu.RUnlock()
+
+	totalDistinct := re.bitSum(re.distinctBIT[:], MaxRating)
 
 	ranks := make([]int, len(ratings))
 	for i, rating := range ratings {
 		if rating >= MinRating && rating <= MaxRating {
-			ranks[i] = 1 + cumulativeAbove[rating]
+			above := totalDistinct - re.bitSum(re.distinctBIT[:], rating)
+			ranks[i] = 1 + above
 		} else {
 			ranks[i] = -1
 		}
@@ -81,7 +139,7 @@ func (re *RankingEngine) GetRankBatch(ratings []int) []int {
 	return ranks
 }
 
-func (re *RankingEngine) UpdateRating(oldRating, newRating int) {
+func (re *RankingEngine) UpdateRating(userID string, oldRating, newRating int) {
 
 	if oldRating == newRating {
 		return
@@ -90,17 +148,9 @@ func (re *RankingEngine) UpdateRating(oldRating, newRating int) {
 	re.mu.Lock()
 	defer re.mu.Unlock()
 
-
-	if oldRating >= MinRating && oldRating <= MaxRating {
-		if re.ratingCount[oldRating] > 0 {
-			re.ratingCount[oldRating]--
-		}
-	}
-
-
-	if newRating >= MinRating && newRating <= MaxRating {
-		re.ratingCount[newRating]++
-	}
+	re.applyRatingChange(oldRating, newRating)
+	recordRatingHistory(userID, oldRating, newRating, re.rankLocked(newRating))
+	topKIndex.Update(userID, oldRating, newRating)
 }
 
 func (re *RankingEngine) BatchUpdateRatings(updates []RatingUpdate) {
@@ -108,22 +158,35 @@ func (re *RankingEngine) BatchUpdateRatings(updates []RatingUpdate) {
 	defer re.mu.Unlock()
 
 	for _, update := range updates {
-	
 		if update.OldRating == update.NewRating {
 			continue
 		}
 
-	
-		if update.OldRating >= MinRating && update.OldRating <= MaxRating {
-			if re.ratingCount[update.OldRating] > 0 {
-				re.ratingCount[update.OldRating]--
+		re.applyRatingChange(update.OldRating, update.NewRating)
+		recordRatingHistory(update.UserID, update.OldRating, update.NewRating, re.rankLocked(update.NewRating))
+		topKIndex.Update(update.UserID, update.OldRating, update.NewRating)
+	}
+}
+
+// applyRatingChange updates ratingCount and both Fenwick trees for a single
+// rating transition. Callers must hold re.mu for writing.
+func (re *RankingEngine) applyRatingChange(oldRating, newRating int) {
+	if oldRating >= MinRating && oldRating <= MaxRating {
+		if re.ratingCount[oldRating] > 0 {
+			re.ratingCount[oldRating]--
+			re.bitAdd(re.userBIT[:], oldRating, -1)
+			if re.ratingCount[oldRating] == 0 {
+				re.bitAdd(re.distinctBIT[:], oldRating, -1)
 			}
 		}
+	}
 
-	
-		if update.NewRating >= MinRating && update.NewRating <= MaxRating {
-			re.ratingCount[update.NewRating]++
+	if newRating >= MinRating && newRating <= MaxRating {
+		if re.ratingCount[newRating] == 0 {
+			re.bitAdd(re.distinctBIT[:], newRating, 1)
 		}
+		re.ratingCount[newRating]++
+		re.bitAdd(re.userBIT[:], newRating, 1)
 	}
 }
 
@@ -151,3 +214,29 @@ func (re *RankingEngine) GetStats() (totalUsers int, uniqueRatings int, minRatin
 func GetRankingEngine() *RankingEngine {
 	return rankingEngine
 }
+
+// RecomputeFromDB rebuilds a fresh RankingSnapshot from storage and swaps it
+// in, for repairing inconsistencies discovered in the live engine without a
+// stop-the-world window.
+func (re *RankingEngine) RecomputeFromDB() error {
+	counts, err := GetRatingCounts()
+	if err != nil {
+		return err
+	}
+
+	snap := &RankingSnapshot{}
+	for rating, count := range counts {
+		if rating < MinRating || rating > MaxRating || count == 0 {
+			continue
+		}
+		snap.ratingCount[rating] = count
+		snap.totalUsers += count
+		for i := rating; i < len(snap.userBIT); i += i & -i {
+			snap.userBIT[i] += count
+			snap.distinctBIT[i]++
+		}
+	}
+
+	re.SwapFrom(snap)
+	return nil
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// GossipChannel is the default Pub/Sub channel used to broadcast rating
+// updates between leaderboard instances.
+const GossipChannel = "leaderboard.updates"
+
+// RatingUpdateMsg is a single rating change broadcast to other nodes.
+// OriginNodeID lets receivers drop echoes of updates they themselves
+// published, and Seq lets a node track how far behind it's fallen.
+type RatingUpdateMsg struct {
+	UserID       string `json:"user_id"`
+	OldRating    int    `json:"old_rating"`
+	NewRating    int    `json:"new_rating"`
+	OriginNodeID string `json:"origin_node_id"`
+	Seq          uint64 `json:"seq"`
+}
+
+// RatingUpdatePublisher broadcasts a rating change to other instances.
+type RatingUpdatePublisher interface {
+	Publish(ctx context.Context, msg RatingUpdateMsg) error
+}
+
+// RatingUpdateSubscriber receives rating changes published by other
+// instances. Subscribe returns a channel that's closed when ctx is done.
+type RatingUpdateSubscriber interface {
+	Subscribe(ctx context.Context) (<-chan RatingUpdateMsg, error)
+	Close() error
+}
+
+// NoOpPublisher is the single-node default: nothing to gossip to.
+type NoOpPublisher struct{}
+
+func (NoOpPublisher) Publish(ctx context.Context, msg RatingUpdateMsg) error {
+	return nil
+}
+
+// RedisPublisher broadcasts rating updates over a Redis Pub/Sub channel.
+type RedisPublisher struct {
+	client  *redis.Client
+	channel string
+}
+
+func NewRedisPublisher(client *redis.Client, channel string) *RedisPublisher {
+	if channel == "" {
+		channel = GossipChannel
+	}
+	return &RedisPublisher{client: client, channel: channel}
+}
+
+func (p *RedisPublisher) Publish(ctx context.Context, msg RatingUpdateMsg) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return p.client.Publish(ctx, p.channel, data).Err()
+}
+
+// RedisSubscriber receives rating updates over a Redis Pub/Sub channel.
+type RedisSubscriber struct {
+	sub *redis.PubSub
+}
+
+func NewRedisSubscriber(client *redis.Client, channel string) *RedisSubscriber {
+	if channel == "" {
+		channel = GossipChannel
+	}
+	return &RedisSubscriber{sub: client.Subscribe(context.Background(), channel)}
+}
+
+func (s *RedisSubscriber) Subscribe(ctx context.Context) (<-chan RatingUpdateMsg, error) {
+	out := make(chan RatingUpdateMsg)
+	raw := s.sub.Channel()
+
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case m, ok := <-raw:
+				if !ok {
+					return
+				}
+				var msg RatingUpdateMsg
+				if err := json.Unmarshal([]byte(m.Payload), &msg); err != nil {
+					log.Printf("gossip: dropping malformed message: %v", err)
+					continue
+				}
+				out <- msg
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func (s *RedisSubscriber) Close() error {
+	return s.sub.Close()
+}
+
+// GossipEngine wraps a RankingEngine with a publisher/subscriber pair so
+// changes applied locally through GossipEngine.UpdateRating are broadcast to
+// other instances in the deployment, and their updates are received and
+// applied here through BatchUpdateRatings. Calls made directly on the wrapped
+// RankingEngine (including its BatchUpdateRatings) are not published. This
+// turns the in-memory RankingEngine into a horizontally
+// scalable, eventually-consistent read layer.
+type GossipEngine struct {
+	engine    *RankingEngine
+	nodeID    string
+	publisher RatingUpdatePublisher
+
+	seq uint64
+
+	// lastAppliedSeqMu guards lastAppliedSeqByOrigin. Seq is a counter local
+	// to whichever node published it, so a single scalar can't represent lag
+	// against more than one remote publisher; track it per origin node ID.
+	lastAppliedSeqMu       sync.Mutex
+	lastAppliedSeqByOrigin map[string]uint64
+}
+
+func NewGossipEngine(engine *RankingEngine, nodeID string, publisher RatingUpdatePublisher) *GossipEngine {
+	if publisher == nil {
+		publisher = NoOpPublisher{}
+	}
+	return &GossipEngine{
+		engine:                 engine,
+		nodeID:                 nodeID,
+		publisher:              publisher,
+		lastAppliedSeqByOrigin: make(map[string]uint64),
+	}
+}
+
+// UpdateRating applies the change locally and publishes it for other nodes.
+func (ge *GossipEngine) UpdateRating(ctx context.Context, userID string, oldRating, newRating int) error {
+	ge.engine.UpdateRating(userID, oldRating, newRating)
+
+	seq := atomic.AddUint64(&ge.seq, 1)
+	return ge.publisher.Publish(ctx, RatingUpdateMsg{
+		UserID:       userID,
+		OldRating:    oldRating,
+		NewRating:    newRating,
+		OriginNodeID: ge.nodeID,
+		Seq:          seq,
+	})
+}
+
+// Listen consumes updates from sub until ctx is cancelled, applying remote
+// updates locally without re-publishing. Messages this node originated are
+// dropped so they aren't double-applied. Each wakeup drains everything
+// currently buffered on the channel into a single batch and applies it
+// through BatchUpdateRatings, so a burst of remote updates takes one write
+// lock on the engine instead of one per message.
+func (ge *GossipEngine) Listen(ctx context.Context, sub RatingUpdateSubscriber) error {
+	updates, err := sub.Subscribe(ctx)
+	if err != nil {
+		return err
+	}
+
+	for msg, ok := <-updates; ok; msg, ok = <-updates {
+		batch := []RatingUpdateMsg{msg}
+	drain:
+		for {
+			select {
+			case m, ok := <-updates:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, m)
+			default:
+				break drain
+			}
+		}
+		ge.applyRemoteBatch(batch)
+	}
+	return nil
+}
+
+// applyRemoteBatch mutates local state from messages received over gossip,
+// without re-publishing them. Messages this node originated are dropped so
+// they aren't double-applied.
+func (ge *GossipEngine) applyRemoteBatch(batch []RatingUpdateMsg) {
+	updates := make([]RatingUpdate, 0, len(batch))
+	maxSeqByOrigin := make(map[string]uint64)
+	for _, msg := range batch {
+		if msg.OriginNodeID == ge.nodeID {
+			continue
+		}
+		updates = append(updates, RatingUpdate{
+			UserID:    msg.UserID,
+			OldRating: msg.OldRating,
+			NewRating: msg.NewRating,
+		})
+		if msg.Seq > maxSeqByOrigin[msg.OriginNodeID] {
+			maxSeqByOrigin[msg.OriginNodeID] = msg.Seq
+		}
+	}
+	if len(updates) == 0 {
+		return
+	}
+
+	ge.engine.BatchUpdateRatings(updates)
+
+	ge.lastAppliedSeqMu.Lock()
+	for origin, seq := range maxSeqByOrigin {
+		if seq > ge.lastAppliedSeqByOrigin[origin] {
+			ge.lastAppliedSeqByOrigin[origin] = seq
+		}
+	}
+	ge.lastAppliedSeqMu.Unlock()
+}
+
+// ResyncFromDB rebuilds local state from the database, for recovering after
+// missed gossip messages.
+func (ge *GossipEngine) ResyncFromDB() error {
+	return ge.engine.RecomputeFromDB()
+}
+
+// LastAppliedSeq returns the Seq of the most recent update applied from
+// originNodeID, for monitoring gossip lag against that node's own Seq
+// counter. It returns 0 if no update from originNodeID has been applied yet.
+func (ge *GossipEngine) LastAppliedSeq(originNodeID string) uint64 {
+	ge.lastAppliedSeqMu.Lock()
+	defer ge.lastAppliedSeqMu.Unlock()
+	return ge.lastAppliedSeqByOrigin[originNodeID]
+}
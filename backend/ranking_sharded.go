@@ -0,0 +1,240 @@
+package main
+
+import (
+	"log"
+	"sync"
+)
+
+// DefaultShardCount is used when InitRankingEngineSharded is called with shards <= 0.
+const DefaultShardCount = 64
+
+// ratingShard owns a contiguous slice of the rating range and its own lock,
+// so UpdateRating only ever contends with callers touching the same shard.
+type ratingShard struct {
+	mu          sync.RWMutex
+	loRating    int
+	hiRating    int
+	ratingCount []int
+	distinct    int // number of ratings in [loRating, hiRating] with ratingCount > 0
+}
+
+// ShardedRankingEngine partitions [MinRating, MaxRating] across a fixed number
+// of shards to reduce lock contention under concurrent UpdateRating/GetRank
+// traffic, at the cost of GetRank only being consistent per-shard rather than
+// globally atomic (see GetRank's doc comment).
+//
+// This is an alternate engine, not a drop-in replacement for RankingEngine:
+// it does not go through RankingEngine.UpdateRating/BatchUpdateRatings, so
+// RatingHistory, TopKIndex, RankingSnapshot/SwapFrom, and the gossip layer
+// are not wired into it. A deployment that switches this mode on loses all
+// of those features until they're integrated here too.
+type ShardedRankingEngine struct {
+	shards          []*ratingShard
+	numShards       int
+	ratingsPerShard int
+}
+
+var shardedRankingEngine *ShardedRankingEngine
+
+// newShardLayout builds the empty shards for a requested shard count,
+// capping to the number of shards actually needed to cover [0, MaxRating]
+// so a coarse request (e.g. 256 shards over a ~5000-wide rating range)
+// never produces a shard whose lo starts past MaxRating.
+func newShardLayout(shards int) *ShardedRankingEngine {
+	if shards <= 0 {
+		shards = DefaultShardCount
+	}
+
+	ratingsPerShard := (RatingBucketSize + shards - 1) / shards
+	neededShards := (RatingBucketSize + ratingsPerShard - 1) / ratingsPerShard
+	if neededShards < shards {
+		shards = neededShards
+	}
+
+	engine := &ShardedRankingEngine{numShards: shards, ratingsPerShard: ratingsPerShard}
+	engine.shards = make([]*ratingShard, shards)
+
+	for i := 0; i < shards; i++ {
+		lo := i * ratingsPerShard
+		hi := lo + ratingsPerShard - 1
+		if hi > MaxRating {
+			hi = MaxRating
+		}
+		engine.shards[i] = &ratingShard{
+			loRating:    lo,
+			hiRating:    hi,
+			ratingCount: make([]int, hi-lo+1),
+		}
+	}
+
+	return engine
+}
+
+func InitRankingEngineSharded(shards int) error {
+	engine := newShardLayout(shards)
+
+	counts, err := GetRatingCounts()
+	if err != nil {
+		return err
+	}
+
+	totalUsers := 0
+	for rating, count := range counts {
+		if rating >= MinRating && rating <= MaxRating {
+			shard := engine.shardFor(rating)
+			if shard.ratingCount[rating-shard.loRating] == 0 && count > 0 {
+				shard.distinct++
+			}
+			shard.ratingCount[rating-shard.loRating] = count
+			totalUsers += count
+		}
+	}
+
+	shardedRankingEngine = engine
+	log.Printf("✓ Sharded ranking engine initialized with %d users across %d shards",
+		totalUsers, engine.numShards)
+	log.Printf("⚠ sharded ranking engine is not wired to RatingHistory, TopKIndex, " +
+		"RankingSnapshot/SwapFrom, or the gossip layer — those features will not " +
+		"see updates applied through this engine until it is integrated with them")
+
+	return nil
+}
+
+// shardFor returns the shard owning rating. Callers must ensure rating is
+// within [MinRating, MaxRating] or this will index out of range.
+func (se *ShardedRankingEngine) shardFor(rating int) *ratingShard {
+	idx := rating / se.ratingsPerShard
+	if idx >= se.numShards {
+		idx = se.numShards - 1
+	}
+	return se.shards[idx]
+}
+
+func (se *ShardedRankingEngine) UpdateRating(oldRating, newRating int) {
+	if oldRating == newRating {
+		return
+	}
+
+	var shards []*ratingShard
+	if oldRating >= MinRating && oldRating <= MaxRating {
+		shards = append(shards, se.shardFor(oldRating))
+	}
+	if newRating >= MinRating && newRating <= MaxRating {
+		shards = append(shards, se.shardFor(newRating))
+	}
+	shards = lockOrderedShards(shards)
+
+	for _, s := range shards {
+		s.mu.Lock()
+	}
+	defer func() {
+		for _, s := range shards {
+			s.mu.Unlock()
+		}
+	}()
+
+	if oldRating >= MinRating && oldRating <= MaxRating {
+		shard := se.shardFor(oldRating)
+		i := oldRating - shard.loRating
+		if shard.ratingCount[i] > 0 {
+			shard.ratingCount[i]--
+			if shard.ratingCount[i] == 0 {
+				shard.distinct--
+			}
+		}
+	}
+
+	if newRating >= MinRating && newRating <= MaxRating {
+		shard := se.shardFor(newRating)
+		i := newRating - shard.loRating
+		if shard.ratingCount[i] == 0 {
+			shard.distinct++
+		}
+		shard.ratingCount[i]++
+	}
+}
+
+// lockOrderedShards dedupes and sorts shards by a stable index so any two
+// callers always acquire overlapping shard locks in the same order.
+func lockOrderedShards(shards []*ratingShard) []*ratingShard {
+	if len(shards) == 2 && shards[0] == shards[1] {
+		return shards[:1]
+	}
+	if len(shards) == 2 && shards[0].loRating > shards[1].loRating {
+		shards[0], shards[1] = shards[1], shards[0]
+	}
+	return shards
+}
+
+// GetRank returns the dense rank of rating, reading each shard's distinct
+// count under its own read lock in descending order. Shards entirely above
+// rating contribute their precomputed distinct count in O(1); only the one
+// shard straddling rating is linear-scanned. Because shards are locked one
+// at a time rather than all at once, the result reflects each shard's state
+// at the moment it was read, not a single consistent instant across the
+// whole engine; use GetRankBatchConsistent when that matters.
+func (se *ShardedRankingEngine) GetRank(rating int) int {
+	if rating < MinRating || rating > MaxRating {
+		return -1
+	}
+
+	above := 0
+	for i := se.numShards - 1; i >= 0; i-- {
+		shard := se.shards[i]
+		if shard.hiRating <= rating {
+			break
+		}
+
+		shard.mu.RLock()
+		if shard.loRating > rating {
+			above += shard.distinct
+		} else {
+			for r := rating + 1; r <= shard.hiRating; r++ {
+				if shard.ratingCount[r-shard.loRating] > 0 {
+					above++
+				}
+			}
+		}
+		shard.mu.RUnlock()
+	}
+	return 1 + above
+}
+
+// GetRankBatchConsistent takes a read lock on every shard up front so all
+// ranks in the batch are computed against a single consistent snapshot.
+func (se *ShardedRankingEngine) GetRankBatchConsistent(ratings []int) []int {
+	for _, shard := range se.shards {
+		shard.mu.RLock()
+	}
+	defer func() {
+		for _, shard := range se.shards {
+			shard.mu.RUnlock()
+		}
+	}()
+
+	distinctAbove := make([]int, RatingBucketSize+1)
+	distinctCount := 0
+	for i := se.numShards - 1; i >= 0; i-- {
+		shard := se.shards[i]
+		for r := shard.hiRating; r >= shard.loRating; r-- {
+			distinctAbove[r] = distinctCount
+			if shard.ratingCount[r-shard.loRating] > 0 {
+				distinctCount++
+			}
+		}
+	}
+
+	ranks := make([]int, len(ratings))
+	for i, rating := range ratings {
+		if rating >= MinRating && rating <= MaxRating {
+			ranks[i] = 1 + distinctAbove[rating]
+		} else {
+			ranks[i] = -1
+		}
+	}
+	return ranks
+}
+
+func GetShardedRankingEngine() *ShardedRankingEngine {
+	return shardedRankingEngine
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+func newTestShardedEngine(shards, users int, seed int64) *ShardedRankingEngine {
+	se := newShardLayout(shards)
+
+	rnd := rand.New(rand.NewSource(seed))
+	for i := 0; i < users; i++ {
+		rating := MinRating + rnd.Intn(MaxRating-MinRating+1)
+		shard := se.shardFor(rating)
+		if shard.ratingCount[rating-shard.loRating] == 0 {
+			shard.distinct++
+		}
+		shard.ratingCount[rating-shard.loRating]++
+	}
+	return se
+}
+
+// runConcurrentMixed drives goroutines doing a 50/50 mix of UpdateRating and
+// GetRank against update/read, blocking until all goroutines finish.
+func runConcurrentMixed(b *testing.B, goroutines, opsPerGoroutine int, update func(rnd *rand.Rand), read func(rnd *rand.Rand)) {
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for g := 0; g < goroutines; g++ {
+		go func(seed int64) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(seed))
+			for i := 0; i < opsPerGoroutine; i++ {
+				if i%2 == 0 {
+					update(rnd)
+				} else {
+					read(rnd)
+				}
+			}
+		}(int64(g))
+	}
+	wg.Wait()
+}
+
+func TestNewShardLayout_DoesNotPanicAtAnyShardCount(t *testing.T) {
+	for _, shards := range []int{1, 16, 32, 64, 100, 128, 150, 200, 256, 300, 500, 1000} {
+		se := newShardLayout(shards)
+		for _, shard := range se.shards {
+			if shard.hiRating < shard.loRating {
+				t.Fatalf("shards=%d: shard has hiRating %d < loRating %d", shards, shard.hiRating, shard.loRating)
+			}
+		}
+		for rating := MinRating; rating <= MaxRating; rating += 97 {
+			shard := se.shardFor(rating)
+			if rating < shard.loRating || rating > shard.hiRating {
+				t.Fatalf("shards=%d: shardFor(%d) returned shard [%d,%d]", shards, rating, shard.loRating, shard.hiRating)
+			}
+		}
+	}
+}
+
+// linearGetRankSharded reproduces GetRank via a full scan across shards, so
+// tests can check the distinct-count fast path against a known-correct walk.
+func linearGetRankSharded(se *ShardedRankingEngine, rating int) int {
+	above := 0
+	for _, shard := range se.shards {
+		for r := shard.loRating; r <= shard.hiRating; r++ {
+			if r > rating && shard.ratingCount[r-shard.loRating] > 0 {
+				above++
+			}
+		}
+	}
+	return 1 + above
+}
+
+func TestShardedGetRank_OutOfRangeReturnsSentinel(t *testing.T) {
+	se := newTestShardedEngine(64, 1000, 1)
+
+	for _, rating := range []int{-50, MinRating - 1, MaxRating + 1, MaxRating + 500} {
+		if got := se.GetRank(rating); got != -1 {
+			t.Errorf("GetRank(%d) = %d, want -1", rating, got)
+		}
+	}
+}
+
+func TestShardedGetRank_MatchesLinearScan(t *testing.T) {
+	se := newTestShardedEngine(64, 2000, 2)
+
+	for rating := MinRating; rating <= MaxRating; rating += 37 {
+		want := linearGetRankSharded(se, rating)
+		if got := se.GetRank(rating); got != want {
+			t.Fatalf("GetRank(%d) = %d, want %d", rating, got, want)
+		}
+	}
+}
+
+// TestShardedGetRank_UsesDistinctCount corrupting a fully-above-rating
+// shard's ratingCount (but not its distinct count) should not change
+// GetRank's result if GetRank is actually trusting shard.distinct for
+// shards that don't straddle the query rating.
+func TestShardedGetRank_UsesDistinctCount(t *testing.T) {
+	se := newTestShardedEngine(64, 2000, 3)
+
+	rating := MinRating
+	want := se.GetRank(rating)
+
+	for _, shard := range se.shards {
+		if shard.loRating > rating {
+			for i := range shard.ratingCount {
+				shard.ratingCount[i] = 0
+			}
+		}
+	}
+
+	if got := se.GetRank(rating); got != want {
+		t.Fatalf("GetRank(%d) = %d after zeroing ratingCount in shards above it, want unchanged %d (GetRank should be using shard.distinct, not ratingCount, for those shards)", rating, got, want)
+	}
+}
+
+func benchmarkShardedContention(b *testing.B, goroutines int) {
+	se := newTestShardedEngine(64, 5000, 1)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		runConcurrentMixed(b, goroutines, 1000,
+			func(rnd *rand.Rand) {
+				se.UpdateRating(MinRating+rnd.Intn(MaxRating-MinRating+1), MinRating+rnd.Intn(MaxRating-MinRating+1))
+			},
+			func(rnd *rand.Rand) {
+				se.GetRank(MinRating + rnd.Intn(MaxRating-MinRating+1))
+			})
+	}
+}
+
+func benchmarkSingleMutexContention(b *testing.B, goroutines int) {
+	re := newTestEngine(5000, 1)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		runConcurrentMixed(b, goroutines, 1000,
+			func(rnd *rand.Rand) {
+				re.UpdateRating("", MinRating+rnd.Intn(MaxRating-MinRating+1), MinRating+rnd.Intn(MaxRating-MinRating+1))
+			},
+			func(rnd *rand.Rand) {
+				re.GetRank(MinRating + rnd.Intn(MaxRating-MinRating+1))
+			})
+	}
+}
+
+func BenchmarkSharded_16Goroutines(b *testing.B)     { benchmarkShardedContention(b, 16) }
+func BenchmarkSharded_64Goroutines(b *testing.B)     { benchmarkShardedContention(b, 64) }
+func BenchmarkSingleMutex_16Goroutines(b *testing.B) { benchmarkSingleMutexContention(b, 16) }
+func BenchmarkSingleMutex_64Goroutines(b *testing.B) { benchmarkSingleMutexContention(b, 64) }
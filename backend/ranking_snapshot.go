@@ -0,0 +1,103 @@
+package main
+
+// RankingSnapshot is an immutable copy of a RankingEngine's state. It answers
+// the same rank/stat queries as RankingEngine but never blocks or is blocked
+// by the live engine's writers, so long-running analytical queries or a
+// background index rebuild can run against it while updates keep flowing.
+type RankingSnapshot struct {
+	ratingCount [RatingBucketSize]int
+	userBIT     [RatingBucketSize + 1]int
+	distinctBIT [RatingBucketSize + 1]int
+	totalUsers  int
+}
+
+// Snapshot atomically copies the engine's state under the write lock and
+// returns it as an immutable RankingSnapshot.
+func (re *RankingEngine) Snapshot() *RankingSnapshot {
+	re.mu.Lock()
+	defer re.mu.Unlock()
+
+	snap := &RankingSnapshot{
+		ratingCount: re.ratingCount,
+		userBIT:     re.userBIT,
+		distinctBIT: re.distinctBIT,
+		totalUsers:  re.totalUsers,
+	}
+	return snap
+}
+
+// SwapFrom atomically replaces the engine's live state with snap's, e.g. to
+// repair inconsistencies discovered by RecomputeFromDB() without a
+// stop-the-world window.
+func (re *RankingEngine) SwapFrom(snap *RankingSnapshot) {
+	re.mu.Lock()
+	defer re.mu.Unlock()
+
+	re.ratingCount = snap.ratingCount
+	re.userBIT = snap.userBIT
+	re.distinctBIT = snap.distinctBIT
+	re.totalUsers = snap.totalUsers
+}
+
+func (rs *RankingSnapshot) bitSum(tree []int, r int) int {
+	s := 0
+	for i := r; i > 0; i -= i & -i {
+		s += tree[i]
+	}
+	return s
+}
+
+// GetRank returns the dense rank of rating, or -1 if rating is outside
+// [MinRating, MaxRating].
+func (rs *RankingSnapshot) GetRank(rating int) int {
+	if rating < MinRating || rating > MaxRating {
+		return -1
+	}
+
+	above := rs.bitSum(rs.distinctBIT[:], MaxRating) - rs.bitSum(rs.distinctBIT[:], rating)
+	return 1 + above
+}
+
+func (rs *RankingSnapshot) GetRankBatch(ratings []int) []int {
+	totalDistinct := rs.bitSum(rs.distinctBIT[:], MaxRating)
+
+	ranks := make([]int, len(ratings))
+	for i, rating := range ratings {
+		if rating >= MinRating && rating <= MaxRating {
+			ranks[i] = 1 + totalDistinct - rs.bitSum(rs.distinctBIT[:], rating)
+		} else {
+			ranks[i] = -1
+		}
+	}
+	return ranks
+}
+
+func (rs *RankingSnapshot) GetStats() (totalUsers int, uniqueRatings int, minRatingWithUsers int, maxRatingWithUsers int) {
+	minRatingWithUsers = -1
+	maxRatingWithUsers = -1
+
+	for r := MinRating; r <= MaxRating; r++ {
+		if rs.ratingCount[r] > 0 {
+			totalUsers += rs.ratingCount[r]
+			uniqueRatings++
+			if minRatingWithUsers == -1 {
+				minRatingWithUsers = r
+			}
+			maxRatingWithUsers = r
+		}
+	}
+
+	return
+}
+
+// Export returns a copy of the snapshot's rating -> user count mapping,
+// omitting ratings with no users.
+func (rs *RankingSnapshot) Export() map[int]int {
+	out := make(map[int]int)
+	for r := MinRating; r <= MaxRating; r++ {
+		if rs.ratingCount[r] > 0 {
+			out[r] = rs.ratingCount[r]
+		}
+	}
+	return out
+}
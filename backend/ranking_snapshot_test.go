@@ -0,0 +1,58 @@
+package main
+
+import "testing"
+
+func TestSnapshot_PreUpdateRanksPreserved(t *testing.T) {
+	re := &RankingEngine{}
+	for _, rating := range []int{200, 300, 400, 500} {
+		re.ratingCount[rating] = 1
+		re.bitAdd(re.userBIT[:], rating, 1)
+		re.bitAdd(re.distinctBIT[:], rating, 1)
+	}
+
+	const probeRating = 300
+	wantRank := re.GetRank(probeRating) // distinct ratings above 300: {400, 500} -> rank 3
+
+	snap := re.Snapshot()
+
+	// Mutate the live engine after taking the snapshot; these changes must
+	// not be visible through snap.
+	re.UpdateRating("alice", 500, 150)
+
+	if got := snap.GetRank(probeRating); got != wantRank {
+		t.Errorf("snap.GetRank(%d) = %d, want pre-update rank %d", probeRating, got, wantRank)
+	}
+
+	liveRank := re.GetRank(probeRating)
+	if liveRank == wantRank {
+		t.Fatalf("test is not exercising a real change: live rank still equals pre-update rank (%d)", wantRank)
+	}
+}
+
+func TestSnapshot_GetRank_OutOfRangeReturnsSentinel(t *testing.T) {
+	re := newTestEngine(1000, 8)
+	snap := re.Snapshot()
+
+	for _, rating := range []int{-1, 0, MaxRating + 1, 999999} {
+		if got := snap.GetRank(rating); got != -1 {
+			t.Errorf("snap.GetRank(%d) = %d, want -1", rating, got)
+		}
+	}
+}
+
+func TestSnapshot_SwapFromRestoresState(t *testing.T) {
+	re := newTestEngine(1000, 7)
+	snap := re.Snapshot()
+	wantTotal, wantUnique, wantMin, wantMax := snap.GetStats()
+
+	re.UpdateRating("dave", MinRating, MaxRating)
+	re.UpdateRating("erin", MinRating+1, MaxRating-1)
+
+	re.SwapFrom(snap)
+
+	gotTotal, gotUnique, gotMin, gotMax := re.GetStats()
+	if gotTotal != wantTotal || gotUnique != wantUnique || gotMin != wantMin || gotMax != wantMax {
+		t.Errorf("GetStats() after SwapFrom = (%d, %d, %d, %d), want (%d, %d, %d, %d)",
+			gotTotal, gotUnique, gotMin, gotMax, wantTotal, wantUnique, wantMin, wantMax)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// linearGetRank reproduces the pre-Fenwick O(R) dense-rank scan, kept here
+// as the reference for TestGetRank_MatchesLinearScan and so the benchmarks
+// below can compare it against the current Fenwick-backed
+// RankingEngine.GetRank.
+func linearGetRank(re *RankingEngine, rating int) int {
+	re.mu.RLock()
+	defer re.mu.RUnlock()
+
+	rank := 1
+	for r := rating + 1; r <= MaxRating; r++ {
+		if re.ratingCount[r] > 0 {
+			rank++
+		}
+	}
+	return rank
+}
+
+func newTestEngine(users int, seed int64) *RankingEngine {
+	re := &RankingEngine{}
+	rnd := rand.New(rand.NewSource(seed))
+	for i := 0; i < users; i++ {
+		rating := MinRating + rnd.Intn(MaxRating-MinRating+1)
+		re.ratingCount[rating]++
+		re.totalUsers++
+		re.bitAdd(re.userBIT[:], rating, 1)
+		if re.ratingCount[rating] == 1 {
+			re.bitAdd(re.distinctBIT[:], rating, 1)
+		}
+	}
+	return re
+}
+
+func TestGetRank_OutOfRangeReturnsSentinel(t *testing.T) {
+	re := newTestEngine(1000, 1)
+
+	for _, rating := range []int{-1, 0, MaxRating + 1, 999999} {
+		if got := re.GetRank(rating); got != -1 {
+			t.Errorf("GetRank(%d) = %d, want -1", rating, got)
+		}
+		if got := re.GetCompetitionRank(rating); got != -1 {
+			t.Errorf("GetCompetitionRank(%d) = %d, want -1", rating, got)
+		}
+		if got := re.GetPercentile(rating); got != -1 {
+			t.Errorf("GetPercentile(%d) = %v, want -1", rating, got)
+		}
+	}
+}
+
+func TestGetRank_MatchesLinearScan(t *testing.T) {
+	re := newTestEngine(2000, 2)
+
+	for rating := MinRating; rating <= MaxRating; rating += 37 {
+		want := linearGetRank(re, rating)
+		if got := re.GetRank(rating); got != want {
+			t.Fatalf("GetRank(%d) = %d, want %d", rating, got, want)
+		}
+	}
+}
+
+func BenchmarkGetRank_Linear(b *testing.B) {
+	re := newTestEngine(5000, 3)
+	rnd := rand.New(rand.NewSource(4))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < 10000; j++ {
+			rating := MinRating + rnd.Intn(MaxRating-MinRating+1)
+			if j%2 == 0 {
+				linearGetRank(re, rating)
+			} else {
+				re.UpdateRating("", rating, MinRating+rnd.Intn(MaxRating-MinRating+1))
+			}
+		}
+	}
+}
+
+func BenchmarkGetRank_Fenwick(b *testing.B) {
+	re := newTestEngine(5000, 3)
+	rnd := rand.New(rand.NewSource(4))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < 10000; j++ {
+			rating := MinRating + rnd.Intn(MaxRating-MinRating+1)
+			if j%2 == 0 {
+				re.GetRank(rating)
+			} else {
+				re.UpdateRating("", rating, MinRating+rnd.Intn(MaxRating-MinRating+1))
+			}
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// HistoryCapacity bounds the number of rating changes retained per user.
+const HistoryCapacity = 50
+
+// RingBuffer is a fixed-size, concurrency-safe circular buffer. Add overwrites
+// the oldest entry once the buffer is full.
+type RingBuffer[T any] struct {
+	mu     sync.Mutex
+	items  []T
+	cap    int
+	next   int
+	length int
+}
+
+func NewRingBuffer[T any](capacity int) *RingBuffer[T] {
+	return &RingBuffer[T]{
+		items: make([]T, capacity),
+		cap:   capacity,
+	}
+}
+
+func (rb *RingBuffer[T]) Add(item T) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	rb.items[rb.next] = item
+	rb.next = (rb.next + 1) % rb.cap
+	if rb.length < rb.cap {
+		rb.length++
+	}
+}
+
+// GetAll returns an ordered copy of the buffer's contents, oldest first.
+func (rb *RingBuffer[T]) GetAll() []T {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	out := make([]T, rb.length)
+	start := rb.next - rb.length
+	if start < 0 {
+		start += rb.cap
+	}
+	for i := 0; i < rb.length; i++ {
+		out[i] = rb.items[(start+i)%rb.cap]
+	}
+	return out
+}
+
+func (rb *RingBuffer[T]) Len() int {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	return rb.length
+}
+
+// RatingChange is one entry in a user's rating history.
+type RatingChange struct {
+	Timestamp  time.Time
+	OldRating  int
+	NewRating  int
+	RankAtTime int
+}
+
+// RatingHistory keeps the last HistoryCapacity rating changes per user. It is
+// keyed by a sync.Map rather than a single mutex-guarded map so that hot
+// users recording changes don't contend with each other.
+type RatingHistory struct {
+	buffers sync.Map // userID string -> *RingBuffer[RatingChange]
+}
+
+func NewRatingHistory() *RatingHistory {
+	return &RatingHistory{}
+}
+
+var ratingHistory = NewRatingHistory()
+
+func (rh *RatingHistory) RecordChange(userID string, change RatingChange) {
+	buf, _ := rh.buffers.LoadOrStore(userID, NewRingBuffer[RatingChange](HistoryCapacity))
+	buf.(*RingBuffer[RatingChange]).Add(change)
+}
+
+// GetRecentChanges returns up to n of the user's most recent rating changes,
+// most recent last. It returns nil if the user has no history or n <= 0.
+func (rh *RatingHistory) GetRecentChanges(userID string, n int) []RatingChange {
+	v, ok := rh.buffers.Load(userID)
+	if !ok {
+		return nil
+	}
+
+	if n <= 0 {
+		return nil
+	}
+
+	all := v.(*RingBuffer[RatingChange]).GetAll()
+	if n >= len(all) {
+		return all
+	}
+	return all[len(all)-n:]
+}
+
+// TrendingUser is one entry in a GetTrendingUsers result, ranked by how many
+// rating changes a user recorded within the lookback window.
+type TrendingUser struct {
+	UserID      string
+	ChangeCount int
+}
+
+// GetTrendingUsers returns up to n users with the most rating changes in the
+// given lookback window, most changes first. It ranges over every tracked
+// user's buffer and scans each buffered entry, so cost scales with the
+// number of users with history times the entries each holds (at most
+// HistoryCapacity).
+func (rh *RatingHistory) GetTrendingUsers(since time.Duration, n int) []TrendingUser {
+	if n <= 0 {
+		return nil
+	}
+
+	cutoff := time.Now().Add(-since)
+	var trending []TrendingUser
+	rh.buffers.Range(func(key, value any) bool {
+		userID := key.(string)
+		buf := value.(*RingBuffer[RatingChange])
+
+		count := 0
+		for _, change := range buf.GetAll() {
+			if change.Timestamp.After(cutoff) {
+				count++
+			}
+		}
+		if count > 0 {
+			trending = append(trending, TrendingUser{UserID: userID, ChangeCount: count})
+		}
+		return true
+	})
+
+	sort.Slice(trending, func(i, j int) bool {
+		return trending[i].ChangeCount > trending[j].ChangeCount
+	})
+	if n < len(trending) {
+		trending = trending[:n]
+	}
+	return trending
+}
+
+// RecentChangesHandler serves GET /users/rating-history?user_id=...&n=20, the
+// "last 20 rating movements" view on a user profile.
+func RecentChangesHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		http.Error(w, "missing userID", http.StatusBadRequest)
+		return
+	}
+
+	n := 20
+	if raw := r.URL.Query().Get("n"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "invalid n", http.StatusBadRequest)
+			return
+		}
+		n = parsed
+	}
+
+	changes := ratingHistory.GetRecentChanges(userID, n)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(changes)
+}
+
+// TrendingUsersHandler serves GET /leaderboard/trending?minutes=60&limit=20,
+// surfacing the users with the most rating changes in the lookback window.
+func TrendingUsersHandler(w http.ResponseWriter, r *http.Request) {
+	minutes := 60
+	if raw := r.URL.Query().Get("minutes"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "invalid minutes", http.StatusBadRequest)
+			return
+		}
+		minutes = parsed
+	}
+
+	limit := 20
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	trending := ratingHistory.GetTrendingUsers(time.Duration(minutes)*time.Minute, limit)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trending)
+}
+
+// recordRatingHistory is the hook UpdateRating/BatchUpdateRatings call after
+// applying a rating change, under the RankingEngine's write lock.
+func recordRatingHistory(userID string, oldRating, newRating, rankAtTime int) {
+	if userID == "" {
+		return
+	}
+	ratingHistory.RecordChange(userID, RatingChange{
+		Timestamp:  time.Now(),
+		OldRating:  oldRating,
+		NewRating:  newRating,
+		RankAtTime: rankAtTime,
+	})
+}
+
+func GetRatingHistory() *RatingHistory {
+	return ratingHistory
+}
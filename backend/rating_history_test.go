@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetTrendingUsers_RanksByChangeCountInWindow(t *testing.T) {
+	rh := NewRatingHistory()
+	now := time.Now()
+
+	record := func(userID string, ts time.Time) {
+		buf, _ := rh.buffers.LoadOrStore(userID, NewRingBuffer[RatingChange](HistoryCapacity))
+		buf.(*RingBuffer[RatingChange]).Add(RatingChange{Timestamp: ts, OldRating: 1000, NewRating: 1010})
+	}
+
+	record("alice", now.Add(-5*time.Minute))
+	record("alice", now.Add(-3*time.Minute))
+	record("alice", now.Add(-2*time.Hour)) // outside the 1h window, shouldn't count
+	record("bob", now.Add(-10*time.Minute))
+	record("carol", now.Add(-90*time.Minute)) // entirely outside the window
+
+	trending := rh.GetTrendingUsers(time.Hour, 10)
+	if len(trending) != 2 {
+		t.Fatalf("got %d trending users, want 2: %+v", len(trending), trending)
+	}
+	if trending[0].UserID != "alice" || trending[0].ChangeCount != 2 {
+		t.Errorf("top trending user = %+v, want alice with 2 changes", trending[0])
+	}
+	if trending[1].UserID != "bob" || trending[1].ChangeCount != 1 {
+		t.Errorf("second trending user = %+v, want bob with 1 change", trending[1])
+	}
+}
+
+func TestGetTrendingUsers_LimitsResults(t *testing.T) {
+	rh := NewRatingHistory()
+	now := time.Now()
+
+	for _, id := range []string{"a", "b", "c"} {
+		buf, _ := rh.buffers.LoadOrStore(id, NewRingBuffer[RatingChange](HistoryCapacity))
+		buf.(*RingBuffer[RatingChange]).Add(RatingChange{Timestamp: now, OldRating: 1000, NewRating: 1010})
+	}
+
+	if got := rh.GetTrendingUsers(time.Hour, 2); len(got) != 2 {
+		t.Errorf("GetTrendingUsers(_, 2) returned %d entries, want 2", len(got))
+	}
+	if got := rh.GetTrendingUsers(time.Hour, 0); got != nil {
+		t.Errorf("GetTrendingUsers(_, 0) = %v, want nil", got)
+	}
+}
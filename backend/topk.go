@@ -0,0 +1,312 @@
+package main
+
+import (
+	"container/heap"
+	"encoding/json"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultTopKSize is used when NewTopKIndex is called with k <= 0.
+const DefaultTopKSize = 1000
+
+// TopKPersistPath is the default file the top-K index is persisted to and
+// reloaded from across restarts.
+const TopKPersistPath = "topk_snapshot.json"
+
+// LeaderboardEntry is a single user/rating pair as surfaced by GetTopK.
+type LeaderboardEntry struct {
+	UserID string
+	Rating int
+}
+
+// TopKDeltaKind distinguishes insertions from evictions in a TopKDelta.
+type TopKDeltaKind int
+
+const (
+	TopKInsert TopKDeltaKind = iota
+	TopKEvict
+)
+
+// TopKDelta describes a single change to the top-K set, suitable for
+// streaming to a frontend over a WebSocket/SSE hook.
+type TopKDelta struct {
+	Kind  TopKDeltaKind
+	Entry LeaderboardEntry
+}
+
+// heapEntry is a tracked top-K member. index is maintained by container/heap
+// so Update can call heap.Fix in O(log K) instead of a linear search.
+type heapEntry struct {
+	userID string
+	rating int
+	index  int
+}
+
+// minHeap is a min-heap over heapEntry.rating: the root is always the lowest
+// rating currently in the top-K, i.e. the next entry to evict.
+type minHeap []*heapEntry
+
+func (h minHeap) Len() int {
+	return len(h)
+}
+
+func (h minHeap) Less(i, j int) bool {
+	return h[i].rating < h[j].rating
+}
+
+func (h minHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *minHeap) Push(x interface{}) {
+	e := x.(*heapEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+func (h *minHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return e
+}
+
+// TopKIndex incrementally maintains the top K users by rating. It is updated
+// from RankingEngine.UpdateRating/BatchUpdateRatings so callers never need to
+// rescan the full rating range to answer "who are the top N users".
+//
+// Only new entrants are checked against the current minimum to decide
+// eviction; once a user is a member, their rating can drop arbitrarily low
+// without being evicted by a rating change alone, since nothing in this
+// engine can tell whether some other, untracked user now outranks them.
+// RebuildFromSnapshot replaces the index's contents wholesale given an
+// authoritative top-K list from elsewhere. InitRankingEngine uses this at
+// startup, seeding from TopKPersistPath when available and falling back to
+// RebuildFromDB otherwise; StartPeriodicPersistence keeps that file current
+// going forward.
+type TopKIndex struct {
+	mu      sync.Mutex
+	k       int
+	heap    minHeap
+	members map[string]*heapEntry
+
+	subMu sync.Mutex
+	subs  []chan<- TopKDelta
+}
+
+func NewTopKIndex(k int) *TopKIndex {
+	if k <= 0 {
+		k = DefaultTopKSize
+	}
+	return &TopKIndex{
+		k:       k,
+		members: make(map[string]*heapEntry),
+	}
+}
+
+// Update applies a rating change for userID. A tracked member's rating is
+// updated in place and the member is never evicted by its own change. An
+// untracked user is inserted while the index has room, or, once it is full,
+// only if newRating beats the current minimum, which is then evicted.
+func (tk *TopKIndex) Update(userID string, oldRating, newRating int) {
+	if userID == "" || oldRating == newRating {
+		return
+	}
+
+	tk.mu.Lock()
+	var evicted, inserted *LeaderboardEntry
+	if entry, ok := tk.members[userID]; ok {
+		entry.rating = newRating
+		heap.Fix(&tk.heap, entry.index)
+	} else if tk.heap.Len() < tk.k {
+		entry := &heapEntry{userID: userID, rating: newRating}
+		heap.Push(&tk.heap, entry)
+		tk.members[userID] = entry
+		inserted = &LeaderboardEntry{UserID: userID, Rating: newRating}
+	} else if tk.heap.Len() > 0 && newRating > tk.heap[0].rating {
+		evictedEntry := heap.Pop(&tk.heap).(*heapEntry)
+		delete(tk.members, evictedEntry.userID)
+		evicted = &LeaderboardEntry{UserID: evictedEntry.userID, Rating: evictedEntry.rating}
+
+		entry := &heapEntry{userID: userID, rating: newRating}
+		heap.Push(&tk.heap, entry)
+		tk.members[userID] = entry
+		inserted = &LeaderboardEntry{UserID: userID, Rating: newRating}
+	}
+	tk.mu.Unlock()
+
+	if evicted != nil {
+		tk.publish(TopKDelta{Kind: TopKEvict, Entry: *evicted})
+	}
+	if inserted != nil {
+		tk.publish(TopKDelta{Kind: TopKInsert, Entry: *inserted})
+	}
+}
+
+// GetTopK returns up to n users sorted by rating descending; n is clamped to
+// [0, K].
+func (tk *TopKIndex) GetTopK(n int) []LeaderboardEntry {
+	tk.mu.Lock()
+	defer tk.mu.Unlock()
+
+	if n < 0 {
+		n = 0
+	}
+	if n > len(tk.heap) {
+		n = len(tk.heap)
+	}
+
+	entries := make([]LeaderboardEntry, len(tk.heap))
+	for i, e := range tk.heap {
+		entries[i] = LeaderboardEntry{UserID: e.userID, Rating: e.rating}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Rating > entries[j].Rating })
+
+	return entries[:n]
+}
+
+// GetUserPosition returns userID's 1-indexed rank within the top-K and
+// whether they're currently tracked at all.
+func (tk *TopKIndex) GetUserPosition(userID string) (rank int, inTopK bool) {
+	tk.mu.Lock()
+	defer tk.mu.Unlock()
+
+	entry, ok := tk.members[userID]
+	if !ok {
+		return -1, false
+	}
+
+	rank = 1
+	for _, e := range tk.heap {
+		if e.rating > entry.rating {
+			rank++
+		}
+	}
+	return rank, true
+}
+
+// SubscribeTopKChanges registers ch to receive future insertions/evictions.
+// Sends are non-blocking: a subscriber that falls behind drops deltas rather
+// than stalling the update path.
+func (tk *TopKIndex) SubscribeTopKChanges(ch chan<- TopKDelta) {
+	tk.subMu.Lock()
+	defer tk.subMu.Unlock()
+
+	tk.subs = append(tk.subs, ch)
+}
+
+func (tk *TopKIndex) publish(delta TopKDelta) {
+	tk.subMu.Lock()
+	defer tk.subMu.Unlock()
+
+	for _, ch := range tk.subs {
+		select {
+		case ch <- delta:
+		default:
+			log.Printf("topk: dropping delta, subscriber channel full")
+		}
+	}
+}
+
+// PersistToDisk writes the current top-K, sorted by rating descending, to
+// path as JSON so it can be reloaded without a full DB scan after a restart.
+func (tk *TopKIndex) PersistToDisk(path string) error {
+	entries := tk.GetTopK(tk.k)
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// LoadFromDisk replaces the index's contents with entries previously written
+// by PersistToDisk.
+func (tk *TopKIndex) LoadFromDisk(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var entries []LeaderboardEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+
+	tk.RebuildFromSnapshot(entries)
+	return nil
+}
+
+// RebuildFromSnapshot replaces the index's contents with entries, an
+// authoritative top-K list from elsewhere (e.g. a persisted snapshot, or a
+// dedicated DB query). entries need not be sorted or pre-truncated; only the
+// top k are kept.
+func (tk *TopKIndex) RebuildFromSnapshot(entries []LeaderboardEntry) {
+	if len(entries) > 1 {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Rating > entries[j].Rating })
+	}
+
+	tk.mu.Lock()
+	defer tk.mu.Unlock()
+
+	if len(entries) > tk.k {
+		entries = entries[:tk.k]
+	}
+
+	tk.heap = make(minHeap, 0, len(entries))
+	tk.members = make(map[string]*heapEntry, len(entries))
+	for _, e := range entries {
+		entry := &heapEntry{userID: e.UserID, rating: e.Rating}
+		heap.Push(&tk.heap, entry)
+		tk.members[e.UserID] = entry
+	}
+}
+
+// RebuildFromDB repopulates the index from storage, for a first boot or a
+// corrupted/missing TopKPersistPath where there's no persisted snapshot to
+// load from yet.
+func (tk *TopKIndex) RebuildFromDB() error {
+	entries, err := GetTopRatedUsers(tk.k)
+	if err != nil {
+		return err
+	}
+
+	tk.RebuildFromSnapshot(entries)
+	return nil
+}
+
+// StartPeriodicPersistence writes the index to path every interval until the
+// returned stop func is called, so the top-K survives a restart without
+// requiring a full DB scan to repopulate.
+func (tk *TopKIndex) StartPeriodicPersistence(path string, interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				if err := tk.PersistToDisk(path); err != nil {
+					log.Printf("topk: periodic persist to %s failed: %v", path, err)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+var topKIndex = NewTopKIndex(DefaultTopKSize)
+
+func GetTopKIndex() *TopKIndex {
+	return topKIndex
+}
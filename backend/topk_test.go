@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestTopKIndex_UpdateInsertsNewMemberUnderK(t *testing.T) {
+	tk := NewTopKIndex(3)
+
+	tk.Update("alice", 0, 1000)
+	tk.Update("bob", 0, 1100)
+
+	if rank, inTopK := tk.GetUserPosition("alice"); !inTopK || rank != 2 {
+		t.Errorf("alice position = (%d, %v), want (2, true)", rank, inTopK)
+	}
+	if rank, inTopK := tk.GetUserPosition("bob"); !inTopK || rank != 1 {
+		t.Errorf("bob position = (%d, %v), want (1, true)", rank, inTopK)
+	}
+
+	top := tk.GetTopK(10)
+	if len(top) != 2 {
+		t.Fatalf("GetTopK(10) returned %d entries, want 2", len(top))
+	}
+	if top[0].UserID != "bob" || top[1].UserID != "alice" {
+		t.Errorf("GetTopK(10) = %+v, want [bob alice]", top)
+	}
+}
+
+func TestTopKIndex_UpdateInsertsWithEviction(t *testing.T) {
+	tk := NewTopKIndex(2)
+
+	tk.Update("alice", 0, 1000)
+	tk.Update("bob", 0, 1100)
+
+	// Index is full; a new member below the current minimum (alice, 1000)
+	// should not be inserted or evict anyone.
+	tk.Update("carol", 0, 900)
+	if _, inTopK := tk.GetUserPosition("carol"); inTopK {
+		t.Errorf("carol should not have entered a full top-K below the current minimum")
+	}
+	if _, inTopK := tk.GetUserPosition("alice"); !inTopK {
+		t.Errorf("alice should still be tracked")
+	}
+
+	// A new member above the current minimum should evict it.
+	tk.Update("dave", 0, 1200)
+	if _, inTopK := tk.GetUserPosition("alice"); inTopK {
+		t.Errorf("alice should have been evicted by dave")
+	}
+	if rank, inTopK := tk.GetUserPosition("dave"); !inTopK || rank != 1 {
+		t.Errorf("dave position = (%d, %v), want (1, true)", rank, inTopK)
+	}
+
+	top := tk.GetTopK(10)
+	if len(top) != 2 {
+		t.Fatalf("GetTopK(10) returned %d entries, want 2", len(top))
+	}
+	if top[0].UserID != "dave" || top[1].UserID != "bob" {
+		t.Errorf("GetTopK(10) = %+v, want [dave bob]", top)
+	}
+}
+
+func TestTopKIndex_UpdateExistingMemberRatingChange(t *testing.T) {
+	tk := NewTopKIndex(3)
+
+	tk.Update("alice", 0, 1000)
+	tk.Update("bob", 0, 1100)
+
+	tk.Update("alice", 1000, 1200)
+
+	if rank, inTopK := tk.GetUserPosition("alice"); !inTopK || rank != 1 {
+		t.Errorf("alice position after rating change = (%d, %v), want (1, true)", rank, inTopK)
+	}
+	if rank, inTopK := tk.GetUserPosition("bob"); !inTopK || rank != 2 {
+		t.Errorf("bob position after alice's rating change = (%d, %v), want (2, true)", rank, inTopK)
+	}
+}
+
+func TestTopKIndex_PersistAndLoadRoundTrip(t *testing.T) {
+	tk := NewTopKIndex(3)
+	tk.Update("alice", 0, 1000)
+	tk.Update("bob", 0, 1100)
+	tk.Update("carol", 0, 1200)
+
+	path := t.TempDir() + "/topk_snapshot.json"
+	if err := tk.PersistToDisk(path); err != nil {
+		t.Fatalf("PersistToDisk: %v", err)
+	}
+	defer os.Remove(path)
+
+	loaded := NewTopKIndex(3)
+	if err := loaded.LoadFromDisk(path); err != nil {
+		t.Fatalf("LoadFromDisk: %v", err)
+	}
+
+	want := tk.GetTopK(10)
+	got := loaded.GetTopK(10)
+	if len(got) != len(want) {
+		t.Fatalf("GetTopK after round trip = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("GetTopK()[%d] after round trip = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}